@@ -0,0 +1,170 @@
+// Copyright (c) 2020 Yannic Wehner
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package fingerprint identifies the service listening on an already-open
+// TCP port: an empty-read banner grab first, then a table of
+// protocol-specific probes (HTTP, TLS, SSH, SMTP/FTP/POP3, MySQL/Redis).
+// Responses are matched against a versioned signature file embedded in the
+// module so fingerprinting keeps working offline.
+package fingerprint
+
+import (
+	"net"
+	"time"
+)
+
+// Options controls how deep Probe goes on each connection.
+type Options struct {
+	// BannerTimeout bounds the initial empty-read banner grab.
+	BannerTimeout time.Duration
+	// ProbeTimeout bounds each protocol-specific probe sent after the
+	// banner grab comes back empty or matches no signature.
+	ProbeTimeout time.Duration
+	// Signatures is consulted to turn a raw banner into a Service/Product/
+	// Version triple. Defaults to DefaultSignatures() when nil.
+	Signatures SignatureSet
+}
+
+// TLSInfo is populated when a port speaks TLS, via a probe ClientHello with
+// ALPN offered and the resulting certificate parsed.
+type TLSInfo struct {
+	Version    string
+	ALPN       string
+	CertCN     string
+	CertIssuer string
+	NotAfter   time.Time
+}
+
+// Result is what a single Probe call found on a port.
+type Result struct {
+	Service string
+	Product string
+	Version string
+	Banner  string
+	TLS     *TLSInfo
+}
+
+// Probe fingerprints the service on the other end of conn. conn must
+// already be an established TCP connection to an Open port; Probe never
+// dials on its own for this first connection. It tries, in order: a short
+// empty-read banner grab (many services, e.g. SSH/FTP/SMTP, greet
+// unprompted), the explicit plaintext protocol probes (Redis PING, GET /
+// HTTP/1.0), and only then a TLS ClientHello, stopping as soon as a
+// signature matches. TLS goes last, and on a fresh connection of its own:
+// unsolicited binary ClientHello bytes written down a connection a
+// plaintext service already got probed on can hard-close or desync that
+// service's stream, which would otherwise make the explicit HTTP probe
+// silently come back empty for the exact common case (plain HTTP) Probe is
+// meant to identify.
+func Probe(conn net.Conn, opts Options) (*Result, error) {
+	sigs := opts.Signatures
+	if sigs == nil {
+		var err error
+		sigs, err = DefaultSignatures()
+		if err != nil {
+			return nil, err
+		}
+	}
+	bannerTimeout := opts.BannerTimeout
+	if bannerTimeout <= 0 {
+		bannerTimeout = 500 * time.Millisecond
+	}
+	probeTimeout := opts.ProbeTimeout
+	if probeTimeout <= 0 {
+		probeTimeout = 2 * time.Second
+	}
+
+	if banner, err := readBanner(conn, bannerTimeout); err == nil && banner != "" {
+		if res := matchBanner(banner, sigs); res != nil {
+			return res, nil
+		}
+	}
+
+	if banner, err := probeRedis(conn, probeTimeout); err == nil && banner != "" {
+		if res := matchBanner(banner, sigs); res != nil {
+			return res, nil
+		}
+	}
+
+	if banner, err := probeHTTP(conn, probeTimeout); err == nil && banner != "" {
+		if res := matchBanner(banner, sigs); res != nil {
+			return res, nil
+		}
+		return &Result{Service: "http", Banner: banner}, nil
+	}
+
+	if tlsInfo, err := probeTLSDial(conn.RemoteAddr(), probeTimeout); err == nil && tlsInfo != nil {
+		return &Result{Service: "tls", TLS: tlsInfo}, nil
+	}
+
+	return &Result{}, nil
+}
+
+func matchBanner(banner string, sigs SignatureSet) *Result {
+	service, product, version, ok := sigs.Match(banner)
+	if !ok {
+		return nil
+	}
+	return &Result{Service: service, Product: product, Version: version, Banner: banner}
+}
+
+func readBanner(conn net.Conn, timeout time.Duration) (string, error) {
+	_ = conn.SetReadDeadline(time.Now().Add(timeout))
+	defer conn.SetReadDeadline(time.Time{})
+
+	buf := make([]byte, 1024)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return "", err
+	}
+	return string(buf[:n]), nil
+}
+
+// probeRedis sends an inline PING: Redis never greets unprompted, so the
+// banner grab alone can never identify it the way it does SSH/FTP/SMTP.
+func probeRedis(conn net.Conn, timeout time.Duration) (string, error) {
+	_ = conn.SetDeadline(time.Now().Add(timeout))
+	defer conn.SetDeadline(time.Time{})
+
+	if _, err := conn.Write([]byte("PING\r\n")); err != nil {
+		return "", err
+	}
+	buf := make([]byte, 256)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return "", err
+	}
+	return string(buf[:n]), nil
+}
+
+func probeHTTP(conn net.Conn, timeout time.Duration) (string, error) {
+	_ = conn.SetDeadline(time.Now().Add(timeout))
+	defer conn.SetDeadline(time.Time{})
+
+	if _, err := conn.Write([]byte("GET / HTTP/1.0\r\n\r\n")); err != nil {
+		return "", err
+	}
+	buf := make([]byte, 2048)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return "", err
+	}
+	return string(buf[:n]), nil
+}
@@ -0,0 +1,112 @@
+// Copyright (c) 2020 Yannic Wehner
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package fingerprint
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+//go:embed signatures.txt
+var embeddedSignatures string
+
+// Signature matches a banner/probe response against a regex and, on match,
+// renders the Service/Product/Version a Result is filled with. Product and
+// Version may reference the regex's capture groups as $1, $2, ...
+type Signature struct {
+	Service string
+	Regex   *regexp.Regexp
+	Product string
+	Version string
+}
+
+// SignatureSet is an ordered list of Signature tried top to bottom; the
+// first match wins, same as nmap-service-probes.
+type SignatureSet []Signature
+
+// DefaultSignatures parses the signature file embedded into the binary via
+// go:embed, so fingerprinting works without any files on disk.
+func DefaultSignatures() (SignatureSet, error) {
+	return parseSignatures(strings.NewReader(embeddedSignatures))
+}
+
+// WithSignatures loads a signature file from disk, in the same format as
+// the embedded default, to pick up newer signatures without a rebuild.
+func WithSignatures(path string) (SignatureSet, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("fingerprint: %w", err)
+	}
+	defer f.Close()
+	return parseSignatures(f)
+}
+
+func parseSignatures(r interface {
+	Read(p []byte) (int, error)
+}) (SignatureSet, error) {
+	var set SignatureSet
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.SplitN(line, "|", 4)
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("fingerprint: malformed signature line: %q", line)
+		}
+		re, err := regexp.Compile(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("fingerprint: bad regex in %q: %w", line, err)
+		}
+		set = append(set, Signature{Service: fields[0], Regex: re, Product: fields[2], Version: fields[3]})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return set, nil
+}
+
+// Match returns the Service/Product/Version of the first signature whose
+// regex matches banner, expanding $1, $2, ... in Product/Version from the
+// regex's capture groups. ok is false if nothing matched.
+func (set SignatureSet) Match(banner string) (service, product, version string, ok bool) {
+	for _, sig := range set {
+		m := sig.Regex.FindStringSubmatch(banner)
+		if m == nil {
+			continue
+		}
+		return sig.Service, expand(sig.Product, m), expand(sig.Version, m), true
+	}
+	return "", "", "", false
+}
+
+func expand(template string, groups []string) string {
+	out := template
+	for i := len(groups) - 1; i >= 1; i-- {
+		out = strings.ReplaceAll(out, fmt.Sprintf("$%d", i), groups[i])
+	}
+	return out
+}
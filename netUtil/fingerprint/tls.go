@@ -0,0 +1,73 @@
+// Copyright (c) 2020 Yannic Wehner
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package fingerprint
+
+import (
+	"crypto/tls"
+	"net"
+	"time"
+)
+
+var tlsVersionNames = map[uint16]string{
+	tls.VersionTLS10: "TLS 1.0",
+	tls.VersionTLS11: "TLS 1.1",
+	tls.VersionTLS12: "TLS 1.2",
+	tls.VersionTLS13: "TLS 1.3",
+}
+
+// probeTLS sends a ClientHello offering the usual HTTP ALPN identifiers and,
+// on a successful handshake, parses the leaf certificate. conn is wrapped,
+// not consumed: the caller still owns closing the underlying connection.
+func probeTLS(conn net.Conn, timeout time.Duration) (*TLSInfo, error) {
+	_ = conn.SetDeadline(time.Now().Add(timeout))
+	defer conn.SetDeadline(time.Time{})
+
+	cli := tls.Client(conn, &tls.Config{
+		InsecureSkipVerify: true,
+		NextProtos:         []string{"h2", "http/1.1"},
+	})
+	if err := cli.Handshake(); err != nil {
+		return nil, err
+	}
+
+	state := cli.ConnectionState()
+	info := &TLSInfo{Version: tlsVersionNames[state.Version], ALPN: state.NegotiatedProtocol}
+	if len(state.PeerCertificates) > 0 {
+		cert := state.PeerCertificates[0]
+		info.CertCN = cert.Subject.CommonName
+		info.CertIssuer = cert.Issuer.CommonName
+		info.NotAfter = cert.NotAfter
+	}
+	return info, nil
+}
+
+// probeTLSDial is probeTLS over a brand-new connection to addr. The TLS
+// probe is speculative and, unlike the protocol probes tried before it, not
+// something a plaintext service can be expected to tolerate on a
+// connection it already got written to — so it always gets one of its own.
+func probeTLSDial(addr net.Addr, timeout time.Duration) (*TLSInfo, error) {
+	conn, err := net.DialTimeout(addr.Network(), addr.String(), timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	return probeTLS(conn, timeout)
+}
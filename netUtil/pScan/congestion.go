@@ -0,0 +1,180 @@
+// Copyright (c) 2020 Yannic Wehner
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package pScan
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ScanPolicy bounds and seeds the AIMD congestion scheduler a scan uses
+// instead of the fixed 3s timeout / ulimit-sized semaphore. It mirrors
+// Nmap's -T0..-T5 timing templates.
+type ScanPolicy struct {
+	Name string
+
+	InitialConcurrency int64
+	MinConcurrency     int64
+	MaxConcurrency     int64
+
+	// RTTFloor/RTTCeiling clamp the computed SRTT+k*RTTVAR timeout.
+	RTTFloor   time.Duration
+	RTTCeiling time.Duration
+	// RTTMultiplier is k in timeout = SRTT + k*RTTVAR.
+	RTTMultiplier float64
+}
+
+var (
+	// ParanoidPolicy scans one port at a time with generous timeouts, for
+	// IDS evasion where being slow matters more than being fast.
+	ParanoidPolicy = ScanPolicy{
+		Name: "Paranoid", InitialConcurrency: 1, MinConcurrency: 1, MaxConcurrency: 1,
+		RTTFloor: 1 * time.Second, RTTCeiling: 20 * time.Second, RTTMultiplier: 4,
+	}
+	// PolitePolicy trades speed for a lighter footprint on the target/network.
+	PolitePolicy = ScanPolicy{
+		Name: "Polite", InitialConcurrency: 4, MinConcurrency: 1, MaxConcurrency: 32,
+		RTTFloor: 500 * time.Millisecond, RTTCeiling: 10 * time.Second, RTTMultiplier: 4,
+	}
+	// NormalPolicy is the default balance of speed and reliability.
+	NormalPolicy = ScanPolicy{
+		Name: "Normal", InitialConcurrency: 32, MinConcurrency: 4, MaxConcurrency: 512,
+		RTTFloor: 100 * time.Millisecond, RTTCeiling: 5 * time.Second, RTTMultiplier: 3,
+	}
+	// AggressivePolicy assumes a fast, reliable network (typical LAN).
+	AggressivePolicy = ScanPolicy{
+		Name: "Aggressive", InitialConcurrency: 256, MinConcurrency: 16, MaxConcurrency: 4096,
+		RTTFloor: 20 * time.Millisecond, RTTCeiling: 1250 * time.Millisecond, RTTMultiplier: 2,
+	}
+	// InsanePolicy sacrifices accuracy for raw speed; expect false Filtered
+	// results on anything but a fast LAN.
+	InsanePolicy = ScanPolicy{
+		Name: "Insane", InitialConcurrency: 1024, MinConcurrency: 64, MaxConcurrency: 8192,
+		RTTFloor: 5 * time.Millisecond, RTTCeiling: 300 * time.Millisecond, RTTMultiplier: 1,
+	}
+)
+
+// limiter is satisfied by both *semaphore.Weighted and *scheduler, letting
+// scanPortConnect/scanPortRaw stay agnostic of which concurrency strategy a
+// scan was configured with.
+type limiter interface {
+	Acquire(ctx context.Context, n int64) error
+	Release(n int64)
+}
+
+// scheduler tracks a smoothed RTT/RTTVAR per-target (TCP-style, RFC 6298)
+// and grows/shrinks its concurrency ceiling via AIMD: +1 on every successful
+// probe, halved on a timeout or "too many open files".
+type scheduler struct {
+	policy ScanPolicy
+
+	mu       sync.Mutex
+	srtt     time.Duration
+	rttvar   time.Duration
+	primed   bool
+	cur      int64
+	inFlight int64
+	cond     *sync.Cond
+}
+
+func newScheduler(policy ScanPolicy) *scheduler {
+	s := &scheduler{policy: policy, cur: policy.InitialConcurrency}
+	s.cond = sync.NewCond(&s.mu)
+	return s
+}
+
+// Acquire blocks until fewer than the current AIMD concurrency ceiling are
+// in flight. n is always 1 for port probes; it exists to satisfy limiter.
+func (s *scheduler) Acquire(ctx context.Context, n int64) error {
+	s.mu.Lock()
+	for s.inFlight >= s.cur {
+		s.cond.Wait()
+		if ctx.Err() != nil {
+			s.mu.Unlock()
+			return ctx.Err()
+		}
+	}
+	s.inFlight += n
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *scheduler) Release(n int64) {
+	s.mu.Lock()
+	s.inFlight -= n
+	s.cond.Broadcast()
+	s.mu.Unlock()
+}
+
+// Timeout returns the current SRTT + k*RTTVAR estimate, clamped to the
+// policy's floor/ceiling. Before any sample has been recorded it returns
+// RTTCeiling, i.e. assume the worst until proven otherwise.
+func (s *scheduler) Timeout() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.primed {
+		return s.policy.RTTCeiling
+	}
+	t := s.srtt + time.Duration(s.policy.RTTMultiplier*float64(s.rttvar))
+	if t < s.policy.RTTFloor {
+		return s.policy.RTTFloor
+	}
+	if t > s.policy.RTTCeiling {
+		return s.policy.RTTCeiling
+	}
+	return t
+}
+
+// OnSuccess records a completed probe's RTT (RFC 6298 EWMA) and additively
+// increases the concurrency ceiling.
+func (s *scheduler) OnSuccess(rtt time.Duration) {
+	s.mu.Lock()
+	if !s.primed {
+		s.srtt = rtt
+		s.rttvar = rtt / 2
+		s.primed = true
+	} else {
+		delta := s.srtt - rtt
+		if delta < 0 {
+			delta = -delta
+		}
+		s.rttvar = s.rttvar + (delta-s.rttvar)/4
+		s.srtt = s.srtt + (rtt-s.srtt)/8
+	}
+	if s.cur < s.policy.MaxConcurrency {
+		s.cur++
+	}
+	s.cond.Broadcast()
+	s.mu.Unlock()
+}
+
+// OnTimeout multiplicatively decreases the concurrency ceiling in response
+// to a timeout or a resource-exhaustion error ("too many open files").
+func (s *scheduler) OnTimeout() {
+	s.mu.Lock()
+	s.cur /= 2
+	if s.cur < s.policy.MinConcurrency {
+		s.cur = s.policy.MinConcurrency
+	}
+	s.cond.Broadcast()
+	s.mu.Unlock()
+}
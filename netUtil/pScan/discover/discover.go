@@ -0,0 +1,102 @@
+// Copyright (c) 2020 Yannic Wehner
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package discover seeds a Targets list from the local network itself
+// rather than requiring the caller to already know which hosts to scan. It
+// complements the ARP-cache path pScan.Target.QueryMac already uses for
+// hosts that don't answer ping: SSDP M-SEARCH and mDNS service discovery
+// both find devices that never show up in an ARP table until addressed
+// directly.
+//
+// Wiring this into the gort CLI as a `--discover ssdp,mdns` flag is left to
+// the command layer; this package only exposes the library entry point.
+package discover
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/ElCap1tan/gort/netUtil/pScan"
+)
+
+// Protocol is one of the discovery mechanisms Discover can run.
+type Protocol string
+
+const (
+	SSDP Protocol = "ssdp"
+	MDNS Protocol = "mdns"
+)
+
+// DiscoverOptions controls which protocols run and how long Discover waits
+// for responses after sending its queries.
+type DiscoverOptions struct {
+	Protocols []Protocol
+	// Timeout bounds how long Discover listens for replies to each query
+	// round. SSDP runs one round; mDNS runs two in sequence (service types,
+	// then their instances), so it listens for up to twice Timeout.
+	Timeout time.Duration
+}
+
+// DefaultDiscoverOptions runs both protocols with a 3s collection window.
+func DefaultDiscoverOptions() DiscoverOptions {
+	return DiscoverOptions{Protocols: []Protocol{SSDP, MDNS}, Timeout: 3 * time.Second}
+}
+
+// Discover runs the requested protocols and folds every device found into
+// a pScan.Targets, ready to hand to Targets.Scan like any other target
+// list. HostName/Vendor are prefilled from the SSDP device XML
+// (friendlyName/manufacturer) or, for mDNS, from the service instance name
+// and the TXT record's "vendor" key where available, and Location is
+// always Local since both
+// protocols are link-local by construction.
+func Discover(ctx context.Context, opts DiscoverOptions) pScan.Targets {
+	var targets pScan.Targets
+	seen := make(map[string]bool)
+
+	add := func(ip, hostName, vendor string) {
+		if ip == "" || seen[ip] {
+			return
+		}
+		seen[ip] = true
+		targets = append(targets, &pScan.Target{
+			InitialTarget: ip,
+			HostName:      pScan.HostName(hostName),
+			Vendor:        vendor,
+			IPAddr:        net.ParseIP(ip),
+			Status:        pScan.Online,
+			Location:      pScan.Local,
+		})
+	}
+
+	for _, proto := range opts.Protocols {
+		switch proto {
+		case SSDP:
+			for _, d := range ssdpSearch(ctx, opts.Timeout) {
+				add(d.IP, d.FriendlyName, d.Manufacturer)
+			}
+		case MDNS:
+			for _, s := range mdnsQuery(ctx, opts.Timeout) {
+				add(s.IP, s.Instance, s.TXT["vendor"])
+			}
+		}
+	}
+	return targets
+}
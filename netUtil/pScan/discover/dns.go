@@ -0,0 +1,212 @@
+// Copyright (c) 2020 Yannic Wehner
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package discover
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// This file implements just enough of RFC 1035's wire format to build mDNS
+// queries and parse PTR/SRV/TXT/A answers back out of the response. It is
+// not a general-purpose DNS library.
+
+const (
+	dnsTypePTR = 12
+	dnsTypeTXT = 16
+	dnsTypeA   = 1
+	dnsTypeSRV = 33
+	dnsClassIN = 1
+)
+
+type dnsRR struct {
+	Name  string
+	Type  uint16
+	Class uint16
+	TTL   uint32
+
+	// Parsed payload, only one of which is populated depending on Type.
+	PTRName string
+	TXT     map[string]string
+	A       net.IP
+	SRVHost string
+	SRVPort uint16
+}
+
+func encodeDNSName(name string) []byte {
+	var out []byte
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		out = append(out, byte(len(label)))
+		out = append(out, label...)
+	}
+	return append(out, 0)
+}
+
+// buildDNSQuery encodes a single-question query for qname/qtype, QU bit
+// left unset (normal multicast question) as mDNS expects.
+func buildDNSQuery(id uint16, qname string, qtype uint16) []byte {
+	header := make([]byte, 12)
+	binary.BigEndian.PutUint16(header[0:2], id)
+	binary.BigEndian.PutUint16(header[4:6], 1) // QDCOUNT
+
+	msg := append(header, encodeDNSName(qname)...)
+	tail := make([]byte, 4)
+	binary.BigEndian.PutUint16(tail[0:2], qtype)
+	binary.BigEndian.PutUint16(tail[2:4], dnsClassIN)
+	return append(msg, tail...)
+}
+
+// decodeDNSName reads a (possibly compressed) name starting at offset and
+// returns it plus the offset just past it in the *original* message.
+func decodeDNSName(msg []byte, offset int) (string, int, error) {
+	var labels []string
+	start := offset
+	jumped := false
+	guard := 0
+	for {
+		guard++
+		if guard > 128 {
+			return "", 0, errors.New("dns: name too long or looping pointer")
+		}
+		if offset >= len(msg) {
+			return "", 0, errors.New("dns: name runs past end of message")
+		}
+		length := int(msg[offset])
+		if length == 0 {
+			offset++
+			break
+		}
+		if length&0xC0 == 0xC0 {
+			if offset+1 >= len(msg) {
+				return "", 0, errors.New("dns: truncated pointer")
+			}
+			ptr := int(binary.BigEndian.Uint16(msg[offset:offset+2]) & 0x3FFF)
+			if !jumped {
+				start = offset + 2
+			}
+			offset = ptr
+			jumped = true
+			continue
+		}
+		offset++
+		if offset+length > len(msg) {
+			return "", 0, errors.New("dns: label runs past end of message")
+		}
+		labels = append(labels, string(msg[offset:offset+length]))
+		offset += length
+	}
+	if !jumped {
+		start = offset
+	}
+	return strings.Join(labels, "."), start, nil
+}
+
+// parseDNSMessage extracts every resource record from the answer,
+// authority and additional sections (mDNS responders commonly put SRV/
+// TXT/A for a service in the additional section of its PTR answer).
+func parseDNSMessage(msg []byte) ([]dnsRR, error) {
+	if len(msg) < 12 {
+		return nil, fmt.Errorf("dns: message too short")
+	}
+	qdCount := int(binary.BigEndian.Uint16(msg[4:6]))
+	anCount := int(binary.BigEndian.Uint16(msg[6:8]))
+	nsCount := int(binary.BigEndian.Uint16(msg[8:10]))
+	arCount := int(binary.BigEndian.Uint16(msg[10:12]))
+
+	offset := 12
+	for i := 0; i < qdCount; i++ {
+		_, next, err := decodeDNSName(msg, offset)
+		if err != nil {
+			return nil, err
+		}
+		offset = next + 4 // QTYPE + QCLASS
+	}
+
+	var records []dnsRR
+	total := anCount + nsCount + arCount
+	for i := 0; i < total; i++ {
+		name, next, err := decodeDNSName(msg, offset)
+		if err != nil {
+			return nil, err
+		}
+		offset = next
+		if offset+10 > len(msg) {
+			return nil, fmt.Errorf("dns: truncated resource record")
+		}
+		rr := dnsRR{
+			Name:  name,
+			Type:  binary.BigEndian.Uint16(msg[offset : offset+2]),
+			Class: binary.BigEndian.Uint16(msg[offset+2:offset+4]) & 0x7FFF,
+			TTL:   binary.BigEndian.Uint32(msg[offset+4 : offset+8]),
+		}
+		rdLength := int(binary.BigEndian.Uint16(msg[offset+8 : offset+10]))
+		rdOffset := offset + 10
+		if rdOffset+rdLength > len(msg) {
+			return nil, fmt.Errorf("dns: truncated rdata")
+		}
+		rdata := msg[rdOffset : rdOffset+rdLength]
+
+		switch rr.Type {
+		case dnsTypePTR:
+			if target, _, err := decodeDNSName(msg, rdOffset); err == nil {
+				rr.PTRName = target
+			}
+		case dnsTypeTXT:
+			rr.TXT = parseTXT(rdata)
+		case dnsTypeA:
+			if len(rdata) == 4 {
+				rr.A = net.IP(rdata)
+			}
+		case dnsTypeSRV:
+			if len(rdata) >= 6 {
+				rr.SRVPort = binary.BigEndian.Uint16(rdata[4:6])
+				if host, _, err := decodeDNSName(msg, rdOffset+6); err == nil {
+					rr.SRVHost = host
+				}
+			}
+		}
+		records = append(records, rr)
+		offset = rdOffset + rdLength
+	}
+	return records, nil
+}
+
+func parseTXT(rdata []byte) map[string]string {
+	txt := make(map[string]string)
+	for i := 0; i < len(rdata); {
+		length := int(rdata[i])
+		i++
+		if i+length > len(rdata) {
+			break
+		}
+		kv := string(rdata[i : i+length])
+		i += length
+		if eq := strings.IndexByte(kv, '='); eq >= 0 {
+			txt[kv[:eq]] = kv[eq+1:]
+		} else if kv != "" {
+			txt[kv] = ""
+		}
+	}
+	return txt
+}
@@ -0,0 +1,174 @@
+// Copyright (c) 2020 Yannic Wehner
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package discover
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	mdnsAddr        = "224.0.0.251:5353"
+	mdnsServiceMeta = "_services._dns-sd._udp.local."
+)
+
+// mdnsService is one resolved advertisement: an instance name plus the
+// address/port/metadata its SRV+TXT+A records carried.
+type mdnsService struct {
+	Instance string
+	IP       string
+	Port     uint16
+	TXT      map[string]string
+}
+
+// mdnsQuery first asks for every advertised service type
+// (_services._dns-sd._udp.local PTR), then re-queries PTR for each type
+// found and resolves the SRV/TXT/A records that come back alongside it.
+func mdnsQuery(ctx context.Context, timeout time.Duration) []mdnsService {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4zero, Port: 0})
+	if err != nil {
+		return nil
+	}
+	defer conn.Close()
+
+	raddr, err := net.ResolveUDPAddr("udp4", mdnsAddr)
+	if err != nil {
+		return nil
+	}
+
+	serviceTypes := queryPTR(ctx, conn, raddr, mdnsServiceMeta, timeout)
+	if len(serviceTypes) == 0 {
+		return nil
+	}
+
+	// Every service type is queried concurrently, each over its own socket,
+	// so the per-type queries together take roughly one timeout window
+	// regardless of how many service types a LAN advertises: the total call
+	// is about two windows (service types, then instances) instead of
+	// timeout*(1+len(serviceTypes)) from querying them one at a time.
+	resultCh := make(chan []mdnsService, len(serviceTypes))
+	var wg sync.WaitGroup
+	for _, svcType := range serviceTypes {
+		svcType := svcType
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4zero, Port: 0})
+			if err != nil {
+				return
+			}
+			defer c.Close()
+			records := queryRaw(ctx, c, raddr, svcType, dnsTypePTR, timeout)
+			resultCh <- resolveInstances(records)
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(resultCh)
+	}()
+
+	var services []mdnsService
+	for r := range resultCh {
+		services = append(services, r...)
+	}
+	return services
+}
+
+// queryPTR sends a PTR query for qname and returns just the PTR targets
+// found in the reply (i.e. the advertised service type names).
+func queryPTR(ctx context.Context, conn *net.UDPConn, raddr *net.UDPAddr, qname string, timeout time.Duration) []string {
+	var out []string
+	for _, rr := range queryRaw(ctx, conn, raddr, qname, dnsTypePTR, timeout) {
+		if rr.Type == dnsTypePTR && rr.PTRName != "" {
+			out = append(out, rr.PTRName)
+		}
+	}
+	return out
+}
+
+func queryRaw(ctx context.Context, conn *net.UDPConn, raddr *net.UDPAddr, qname string, qtype uint16, timeout time.Duration) []dnsRR {
+	query := buildDNSQuery(1, qname, qtype)
+	if _, err := conn.WriteTo(query, raddr); err != nil {
+		return nil
+	}
+
+	_ = conn.SetReadDeadline(time.Now().Add(timeout))
+	buf := make([]byte, 4096)
+	var all []dnsRR
+	for {
+		if ctx.Err() != nil {
+			break
+		}
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			break
+		}
+		rrs, err := parseDNSMessage(buf[:n])
+		if err != nil {
+			continue
+		}
+		all = append(all, rrs...)
+	}
+	return all
+}
+
+// resolveInstances folds a flat list of records from one PTR query's
+// response into per-instance services by joining PTR->SRV->A on name and
+// TXT on the same owner name as the PTR target.
+func resolveInstances(records []dnsRR) []mdnsService {
+	srv := make(map[string]dnsRR)
+	txt := make(map[string]map[string]string)
+	a := make(map[string]net.IP)
+	var instances []string
+
+	for _, rr := range records {
+		switch rr.Type {
+		case dnsTypePTR:
+			instances = append(instances, rr.PTRName)
+		case dnsTypeSRV:
+			srv[rr.Name] = rr
+		case dnsTypeTXT:
+			txt[rr.Name] = rr.TXT
+		case dnsTypeA:
+			a[rr.Name] = rr.A
+		}
+	}
+
+	var services []mdnsService
+	for _, instance := range instances {
+		s := mdnsService{Instance: unescapeInstance(instance), TXT: txt[instance]}
+		if rec, ok := srv[instance]; ok {
+			s.Port = rec.SRVPort
+			if ip, ok := a[rec.SRVHost]; ok {
+				s.IP = ip.String()
+			}
+		}
+		services = append(services, s)
+	}
+	return services
+}
+
+func unescapeInstance(name string) string {
+	return strings.ReplaceAll(name, "\\.", ".")
+}
@@ -0,0 +1,160 @@
+// Copyright (c) 2020 Yannic Wehner
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package discover
+
+import (
+	"context"
+	"encoding/xml"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const ssdpAddr = "239.255.255.250:1900"
+
+var ssdpSearchRequest = "M-SEARCH * HTTP/1.1\r\n" +
+	"HOST: 239.255.255.250:1900\r\n" +
+	"MAN: \"ssdp:discover\"\r\n" +
+	"MX: 2\r\n" +
+	"ST: ssdp:all\r\n\r\n"
+
+// ssdpDevice is one answer to an M-SEARCH, with the device XML already
+// fetched and parsed where possible.
+type ssdpDevice struct {
+	IP           string
+	Location     string
+	FriendlyName string
+	Manufacturer string
+}
+
+type upnpDeviceDoc struct {
+	XMLName xml.Name `xml:"root"`
+	Device  struct {
+		FriendlyName string `xml:"friendlyName"`
+		Manufacturer string `xml:"manufacturer"`
+	} `xml:"device"`
+}
+
+// ssdpSearch multicasts an M-SEARCH to 239.255.255.250:1900 with
+// ST: ssdp:all, collects replies for timeout, and fetches each LOCATION URL
+// to pull friendlyName/manufacturer out of the device description XML.
+func ssdpSearch(ctx context.Context, timeout time.Duration) []ssdpDevice {
+	raddr, err := net.ResolveUDPAddr("udp4", ssdpAddr)
+	if err != nil {
+		return nil
+	}
+	conn, err := net.ListenUDP("udp4", nil)
+	if err != nil {
+		return nil
+	}
+	defer conn.Close()
+
+	if _, err := conn.WriteTo([]byte(ssdpSearchRequest), raddr); err != nil {
+		return nil
+	}
+
+	deadline := time.Now().Add(timeout)
+	_ = conn.SetReadDeadline(deadline)
+
+	type reply struct {
+		ip       string
+		location string
+	}
+	var replies []reply
+	buf := make([]byte, 2048)
+	for {
+		if ctx.Err() != nil {
+			break
+		}
+		n, from, err := conn.ReadFrom(buf)
+		if err != nil {
+			break
+		}
+		location := headerValue(string(buf[:n]), "LOCATION")
+		if location == "" {
+			continue
+		}
+		replies = append(replies, reply{ip: udpHost(from), location: location})
+	}
+
+	// Device XML is fetched after the fixed-deadline UDP receive loop above
+	// has finished, and concurrently across replies, so one slow device
+	// (each fetch is independently capped at its own 2s) can't stall behind
+	// the single collection window the way a serial fetch loop would.
+	devices := make([]ssdpDevice, len(replies))
+	var wg sync.WaitGroup
+	for i, r := range replies {
+		wg.Add(1)
+		go func(i int, r reply) {
+			defer wg.Done()
+			dev := ssdpDevice{IP: r.ip, Location: r.location}
+			if doc, err := fetchDeviceDoc(r.location); err == nil {
+				dev.FriendlyName = doc.Device.FriendlyName
+				dev.Manufacturer = doc.Device.Manufacturer
+			}
+			devices[i] = dev
+		}(i, r)
+	}
+	wg.Wait()
+	return devices
+}
+
+func fetchDeviceDoc(location string) (*upnpDeviceDoc, error) {
+	if _, err := url.Parse(location); err != nil {
+		return nil, err
+	}
+	cli := http.Client{Timeout: 2 * time.Second}
+	resp, err := cli.Get(location)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var doc upnpDeviceDoc
+	if err := xml.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+func headerValue(msg, key string) string {
+	for _, line := range strings.Split(msg, "\r\n") {
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		if strings.EqualFold(strings.TrimSpace(parts[0]), key) {
+			return strings.TrimSpace(parts[1])
+		}
+	}
+	return ""
+}
+
+func udpHost(addr net.Addr) string {
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return host
+}
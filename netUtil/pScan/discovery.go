@@ -0,0 +1,160 @@
+// Copyright (c) 2020 Yannic Wehner
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package pScan
+
+import (
+	"encoding/binary"
+	"net"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+)
+
+// HostDiscovery is a single way of telling whether a host is alive. Target
+// tries every HostDiscovery it's given and is Online as soon as one of them
+// succeeds, recording which one on DiscoveredVia so callers can tell an
+// ICMP-reachable host from one that only answered a TCP SYN ping.
+type HostDiscovery interface {
+	Name() string
+	Probe(t *Target, privileged bool) (bool, error)
+}
+
+// DefaultHostDiscovery reproduces NewTarget's historical behaviour: ICMP
+// echo only.
+func DefaultHostDiscovery() []HostDiscovery {
+	return []HostDiscovery{ICMPEcho{}}
+}
+
+// discoverHost runs every probe in order and stops at the first success,
+// recording its name on t.DiscoveredVia. A host all probes fail is left
+// Unknown/OfflineFiltered exactly as before this existed.
+func discoverHost(t *Target, discovery []HostDiscovery, privileged bool) {
+	for _, d := range discovery {
+		online, err := d.Probe(t, privileged)
+		if err != nil {
+			continue
+		}
+		if online {
+			t.Status = Online
+			t.DiscoveredVia = d.Name()
+			return
+		}
+	}
+}
+
+// ICMPEcho is the classic ping: three ICMP echo requests via Target.Ping.
+// Any host that filters ICMP echo (common on Windows and hardened Linux
+// boxes) is invisible to it, which is why the other HostDiscovery
+// implementations in this file exist.
+type ICMPEcho struct{ Count int }
+
+func (p ICMPEcho) Name() string { return "icmp-echo" }
+
+func (p ICMPEcho) Probe(t *Target, privileged bool) (bool, error) {
+	count := p.Count
+	if count <= 0 {
+		count = 3
+	}
+	stats, err := t.Ping(count, privileged)
+	if err != nil {
+		return false, err
+	}
+	return stats.PacketsRecv > 0, nil
+}
+
+// timestampBody is an ICMP timestamp request/reply body (RFC 792): an
+// identifier, a sequence number and three 32-bit milliseconds-since-midnight
+// timestamps. golang.org/x/net/icmp only ships Echo/DstUnreach/TimeExceeded
+// bodies, so this implements icmp.MessageBody itself the way this repo's DNS
+// code hand-rolls RFC 1035 wire formats elsewhere.
+type timestampBody struct {
+	ID, Seq int
+}
+
+func (b *timestampBody) Len(proto int) int { return 16 }
+
+func (b *timestampBody) Marshal(proto int) ([]byte, error) {
+	wb := make([]byte, 16)
+	binary.BigEndian.PutUint16(wb[0:2], uint16(b.ID))
+	binary.BigEndian.PutUint16(wb[2:4], uint16(b.Seq))
+	// Originate/receive/transmit timestamps are left zero; only the
+	// reply's ICMP type matters for liveness detection.
+	return wb, nil
+}
+
+// ICMPTimestamp sends an ICMP timestamp request (type 13); some firewalls
+// that drop echo requests still answer it.
+type ICMPTimestamp struct{ Timeout time.Duration }
+
+func (p ICMPTimestamp) Name() string { return "icmp-timestamp" }
+
+func (p ICMPTimestamp) Probe(t *Target, privileged bool) (bool, error) {
+	timeout := p.Timeout
+	if timeout <= 0 {
+		timeout = 1 * time.Second
+	}
+
+	network := "udp4"
+	if privileged {
+		network = "ip4:icmp"
+	}
+	conn, err := icmp.ListenPacket(network, "0.0.0.0")
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	msg := icmp.Message{
+		Type: ipv4.ICMPTypeTimestamp,
+		Code: 0,
+		Body: &timestampBody{
+			ID:  int(t.IPAddr[len(t.IPAddr)-1]) | 1<<8,
+			Seq: 1,
+		},
+	}
+	wb, err := msg.Marshal(nil)
+	if err != nil {
+		return false, err
+	}
+	// A raw ip4:icmp PacketConn requires a *net.IPAddr destination, not
+	// *net.UDPAddr — passing the wrong type here fails the write outright,
+	// silently taking the whole privileged path out of service.
+	var dst net.Addr = &net.UDPAddr{IP: t.IPAddr}
+	if privileged {
+		dst = &net.IPAddr{IP: t.IPAddr}
+	}
+	if _, err := conn.WriteTo(wb, dst); err != nil {
+		return false, err
+	}
+
+	_ = conn.SetReadDeadline(time.Now().Add(timeout))
+	rb := make([]byte, 512)
+	n, _, err := conn.ReadFrom(rb)
+	if err != nil {
+		return false, nil
+	}
+	reply, err := icmp.ParseMessage(1, rb[:n])
+	if err != nil {
+		return false, nil
+	}
+	return reply.Type == ipv4.ICMPTypeTimestampReply, nil
+}
@@ -0,0 +1,66 @@
+// Copyright (c) 2020 Yannic Wehner
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package pScan
+
+import (
+	"time"
+
+	"github.com/mdlayher/arp"
+)
+
+// ARPPing only makes sense for targets on the local subnet, same as the
+// ARP lookup QueryMac already does for MAC discovery: it asks "who has
+// this IP" and treats any answer as the host being online, regardless of
+// whether it would ever reply to ICMP or TCP.
+type ARPPing struct{ Timeout time.Duration }
+
+func (p ARPPing) Name() string { return "arp-ping" }
+
+func (p ARPPing) Probe(t *Target, privileged bool) (bool, error) {
+	timeout := p.Timeout
+	if timeout <= 0 {
+		timeout = 500 * time.Millisecond
+	}
+
+	iface, err := t.routedInterface()
+	if err != nil {
+		return false, err
+	}
+	arpCli, err := arp.Dial(iface)
+	if err != nil {
+		return false, err
+	}
+	defer arpCli.Close()
+
+	if err := arpCli.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return false, err
+	}
+	hwAddr, err := arpCli.Resolve(t.IPAddr)
+	if err != nil {
+		return false, nil
+	}
+	if hwAddr.String() == "00:00:00:00:00:00" {
+		return false, nil
+	}
+	t.Location = Local
+	t.MACAddr = hwAddr
+	return true, nil
+}
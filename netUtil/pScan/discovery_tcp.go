@@ -0,0 +1,214 @@
+// Copyright (c) 2020 Yannic Wehner
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package pScan
+
+import (
+	"encoding/binary"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/ElCap1tan/gort/netUtil/rawtcp"
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+)
+
+var defaultPingPorts = []uint16{80, 443, 22, 3389}
+
+// TCPSYNPing sends a bare SYN to each of Ports and considers the host
+// online if anything at all comes back (SYN/ACK for an open port, RST for
+// a closed one) — unlike a port scan it doesn't care which.
+type TCPSYNPing struct {
+	Ports   []uint16
+	Timeout time.Duration
+}
+
+func (p TCPSYNPing) Name() string { return "tcp-syn-ping" }
+
+func (p TCPSYNPing) Probe(t *Target, privileged bool) (bool, error) {
+	return t.rawTCPPing(p.Ports, p.Timeout, func(s *rawtcp.Sender, dstPort, srcPort uint16) error {
+		return s.SendSYN(t.IPAddr, dstPort, srcPort)
+	}, func(r rawtcp.Reply) bool { return r.Flags.SYN || r.Flags.RST })
+}
+
+// TCPACKPing sends a bare ACK to each of Ports. Stateless firewalls that
+// block unsolicited SYNs often pass a stray ACK straight through, and the
+// host answers with a RST regardless of whether the port is open — useful
+// for finding hosts past that class of firewall.
+type TCPACKPing struct {
+	Ports   []uint16
+	Timeout time.Duration
+}
+
+func (p TCPACKPing) Name() string { return "tcp-ack-ping" }
+
+func (p TCPACKPing) Probe(t *Target, privileged bool) (bool, error) {
+	return t.rawTCPPing(p.Ports, p.Timeout, func(s *rawtcp.Sender, dstPort, srcPort uint16) error {
+		return s.SendACK(t.IPAddr, dstPort, srcPort)
+	}, func(r rawtcp.Reply) bool { return r.Flags.RST })
+}
+
+// rawTCPPing is the shared SYN/ACK ping plumbing: pick a routed interface
+// and MAC the same way scanPortRaw does, send one probe per port, and
+// return true on the first reply that satisfies accept.
+func (t *Target) rawTCPPing(ports []uint16, timeout time.Duration, send func(*rawtcp.Sender, uint16, uint16) error, accept func(rawtcp.Reply) bool) (bool, error) {
+	if len(ports) == 0 {
+		ports = defaultPingPorts
+	}
+	if timeout <= 0 {
+		timeout = 1 * time.Second
+	}
+
+	iface, err := t.routedInterface()
+	if err != nil {
+		return false, err
+	}
+	gwMAC, srcIP, err := t.rawtcpEndpoints(iface)
+	if err != nil {
+		return false, err
+	}
+
+	srcPort := rawtcp.RandSrcPort()
+	listener, err := rawtcp.NewListener(iface, srcPort)
+	if err != nil {
+		return false, err
+	}
+	defer listener.Close()
+	go listener.Run()
+
+	sender, err := rawtcp.NewSender(iface, srcIP, gwMAC)
+	if err != nil {
+		return false, err
+	}
+	defer sender.Close()
+
+	for _, port := range ports {
+		replyCh := listener.Register(t.IPAddr, port, srcPort)
+		if err := send(sender, port, srcPort); err != nil {
+			continue
+		}
+		if reply, ok := rawtcp.WaitReply(replyCh, timeout); ok && accept(reply) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// UDPPing sends an empty UDP datagram to each of Ports, all expected to be
+// closed, and listens for the resulting ICMP "port unreachable" — hosts
+// behind firewalls that drop everything except that one ICMP type still
+// show up this way.
+type UDPPing struct {
+	Ports   []uint16
+	Timeout time.Duration
+}
+
+func (p UDPPing) Name() string { return "udp-ping" }
+
+func (p UDPPing) Probe(t *Target, privileged bool) (bool, error) {
+	ports := p.Ports
+	if len(ports) == 0 {
+		ports = []uint16{40125, 40126, 40127}
+	}
+	timeout := p.Timeout
+	if timeout <= 0 {
+		timeout = 1 * time.Second
+	}
+
+	icmpConn, err := icmp.ListenPacket("ip4:icmp", "0.0.0.0")
+	if err != nil {
+		return false, err
+	}
+	defer icmpConn.Close()
+
+	// Track the ephemeral source port the kernel picked for each dial: a
+	// raw ip4:icmp socket sees every unreachable hitting the host, so
+	// matching on the embedded original datagram's destination IP/port
+	// alone isn't enough when ParseHostString/AsyncNewTarget run many
+	// targets' discovery concurrently — a different target's own UDPPing
+	// probe against the same candidate port would otherwise count here too.
+	sentSrcPorts := make(map[uint16]bool, len(ports))
+	for _, port := range ports {
+		udpConn, err := net.Dial("udp4", net.JoinHostPort(t.IPAddr.String(), strconv.Itoa(int(port))))
+		if err != nil {
+			continue
+		}
+		if local, ok := udpConn.LocalAddr().(*net.UDPAddr); ok {
+			sentSrcPorts[uint16(local.Port)] = true
+		}
+		_, _ = udpConn.Write(nil)
+		udpConn.Close()
+	}
+	if len(sentSrcPorts) == 0 {
+		return false, nil
+	}
+
+	deadline := time.Now().Add(timeout)
+	buf := make([]byte, 512)
+	for {
+		if remaining := time.Until(deadline); remaining <= 0 {
+			return false, nil
+		}
+		_ = icmpConn.SetReadDeadline(deadline)
+		n, _, err := icmpConn.ReadFrom(buf)
+		if err != nil {
+			return false, nil
+		}
+		msg, err := icmp.ParseMessage(1, buf[:n])
+		if err != nil {
+			continue
+		}
+		if msg.Type != ipv4.ICMPTypeDestinationUnreachable {
+			continue
+		}
+		dstUnreach, ok := msg.Body.(*icmp.DstUnreach)
+		if !ok {
+			continue
+		}
+		if matchesUDPProbe(dstUnreach.Data, t.IPAddr, sentSrcPorts) {
+			return true, nil
+		}
+	}
+}
+
+// matchesUDPProbe validates the original-datagram IP/UDP headers an ICMP
+// "destination unreachable" embeds against the UDP probe actually sent:
+// same destination IP, a UDP payload, and a source port this probe itself
+// picked for one of its dials.
+func matchesUDPProbe(embedded []byte, dstIP net.IP, sentSrcPorts map[uint16]bool) bool {
+	if len(embedded) < 20 {
+		return false
+	}
+	ihl := int(embedded[0]&0x0f) * 4
+	if ihl < 20 || len(embedded) < ihl+8 {
+		return false
+	}
+	const protoUDP = 17
+	if embedded[9] != protoUDP {
+		return false
+	}
+	origDstIP := net.IP(embedded[16:20])
+	if !origDstIP.Equal(dstIP) {
+		return false
+	}
+	srcPort := binary.BigEndian.Uint16(embedded[ihl : ihl+2])
+	return sentSrcPorts[srcPort]
+}
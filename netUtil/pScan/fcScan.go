@@ -24,6 +24,8 @@ import (
 	"context"
 	"github.com/ElCap1tan/gort/internal/helper/ulimit"
 	"github.com/ElCap1tan/gort/netUtil"
+	"github.com/ElCap1tan/gort/netUtil/fingerprint"
+	"github.com/ElCap1tan/gort/netUtil/rawtcp"
 	"net"
 	"strconv"
 	"strings"
@@ -33,46 +35,63 @@ import (
 )
 
 func (t Targets) Scan() MultiScanResult {
+	return t.ScanWithOptions(DefaultScanOptions())
+}
+
+// ScanWithOptions is Scan with the probe strategy (see ScanOptions.Mode)
+// and per-port timeout made explicit instead of hardcoded.
+func (t Targets) ScanWithOptions(opts ScanOptions) MultiScanResult {
 	var multiScanRes MultiScanResult
 	out := make(chan *ScanResult)
 
-	var limit int64
-	l, err := ulimit.GetUlimit()
-	if err != nil {
-		limit = 1024
-	} else {
-		limit = int64(l)
+	// The AIMD scheduler tracks one target's own RTT/loss ratio (see
+	// ScanPolicy), so it can't be shared across a batch without mixing
+	// unrelated targets' samples into one EWMA and letting a single
+	// slow/lossy target starve the rest of their concurrency ceiling: each
+	// target gets its own. The plain ulimit semaphore instead bounds a real
+	// process-wide resource (open file descriptors), so that one stays
+	// shared across the whole batch.
+	var shared limiter
+	if opts.Policy == nil {
+		shared = newLimiter(opts)
 	}
 
-	lock := semaphore.NewWeighted(limit)
-	for _, t := range t {
-		if t.IPAddr == nil {
-			multiScanRes.Unresolved = append(multiScanRes.Unresolved, t)
-		} else {
-			go t.scan(out, lock)
+	pending := 0
+	for _, tgt := range t {
+		if tgt.IPAddr == nil {
+			multiScanRes.Unresolved = append(multiScanRes.Unresolved, tgt)
+			continue
+		}
+		lock := shared
+		if lock == nil {
+			lock = newLimiter(opts)
 		}
+		go tgt.scan(out, lock, opts)
+		pending++
 	}
-	for i := 0; i < len(t)-len(multiScanRes.Unresolved); i++ {
+	for i := 0; i < pending; i++ {
 		multiScanRes.Resolved = append(multiScanRes.Resolved, <-out)
 	}
 	return multiScanRes
 }
 
 func (t *Target) Scan() *ScanResult {
+	return t.ScanWithOptions(DefaultScanOptions())
+}
+
+// ScanWithOptions is Scan with the probe strategy (see ScanOptions.Mode)
+// and per-port timeout made explicit instead of hardcoded.
+func (t *Target) ScanWithOptions(opts ScanOptions) *ScanResult {
 	r := NewScanResult(t, time.Now())
 	ch := make(chan *PortResults)
 
-	var limit int64
-	l, err := ulimit.GetUlimit()
-	if err != nil {
-		limit = 1024
-	} else {
-		limit = int64(l)
+	lock := newLimiter(opts)
+	sess := t.maybeRawSession(opts)
+	if sess != nil {
+		defer sess.Close()
 	}
-
-	lock := semaphore.NewWeighted(limit)
 	for _, p := range t.Ports {
-		go t.scanPort(p, ch, lock)
+		go t.scanPort(p, ch, lock, opts, sess)
 	}
 	for range t.Ports {
 		pI := <-ch
@@ -84,11 +103,33 @@ func (t *Target) Scan() *ScanResult {
 	return r
 }
 
-func (t *Target) scan(out chan *ScanResult, lock *semaphore.Weighted) {
+// newLimiter builds the concurrency gate a scan dispatches probes through.
+// With no ScanPolicy set this is the historical ulimit-sized semaphore;
+// with one set it is an AIMD scheduler that grows/shrinks with observed
+// RTT and loss (see ScanPolicy, scheduler).
+func newLimiter(opts ScanOptions) limiter {
+	if opts.Policy != nil {
+		return newScheduler(*opts.Policy)
+	}
+	var limit int64
+	l, err := ulimit.GetUlimit()
+	if err != nil {
+		limit = 1024
+	} else {
+		limit = int64(l)
+	}
+	return semaphore.NewWeighted(limit)
+}
+
+func (t *Target) scan(out chan *ScanResult, lock limiter, opts ScanOptions) {
 	r := NewScanResult(t, time.Now())
 	ch := make(chan *PortResults)
+	sess := t.maybeRawSession(opts)
+	if sess != nil {
+		defer sess.Close()
+	}
 	for _, p := range t.Ports {
-		go t.scanPort(p, ch, lock)
+		go t.scanPort(p, ch, lock, opts, sess)
 	}
 	for range t.Ports {
 		pI := <-ch
@@ -100,18 +141,49 @@ func (t *Target) scan(out chan *ScanResult, lock *semaphore.Weighted) {
 	out <- r
 }
 
-func (t *Target) scanPort(p *netUtil.Port, ch chan *PortResults, lock *semaphore.Weighted) {
+func (t *Target) scanPort(p *netUtil.Port, ch chan *PortResults, lock limiter, opts ScanOptions, sess *rawSession) {
+	switch opts.Mode {
+	case ModeSYN, ModeFIN, ModeNULL, ModeXmas:
+		if sess == nil {
+			// maybeRawSession already failed (and logged nothing further to
+			// say); report every port on this target Filtered rather than
+			// silently falling back to a connect scan.
+			res := NewPortResults()
+			res.Filtered = append(res.Filtered, p)
+			ch <- res
+			return
+		}
+		t.scanPortRaw(p, ch, lock, opts, sess)
+	default:
+		t.scanPortConnect(p, ch, lock, opts.Timeout, opts.Fingerprint)
+	}
+}
+
+func (t *Target) scanPortConnect(p *netUtil.Port, ch chan *PortResults, lock limiter, timeOut time.Duration, fpOpts *fingerprint.Options) {
 	res := NewPortResults()
-	milli := 3000
-	timeOut := time.Duration(milli) * time.Millisecond
+	sched, adaptive := lock.(*scheduler)
+	if adaptive {
+		timeOut = sched.Timeout()
+	} else if timeOut <= 0 {
+		timeOut = 3000 * time.Millisecond
+	}
 	lock.Acquire(context.TODO(), 1)
+	start := time.Now()
 	conn, err := net.DialTimeout("tcp", t.IPAddr.String()+":"+strconv.Itoa(int(p.PortNo)), timeOut)
 	if err == nil {
 		defer conn.Close()
 		t.Status = Online
 		res.Open = append(res.Open, p)
-		ch <- res
+		if adaptive {
+			sched.OnSuccess(time.Since(start))
+		}
 		lock.Release(1)
+		if fpOpts != nil {
+			if fp, fpErr := fingerprint.Probe(conn, *fpOpts); fpErr == nil {
+				p.Service, p.Product, p.Version, p.Banner, p.TLSInfo = fp.Service, fp.Product, fp.Version, fp.Banner, fp.TLS
+			}
+		}
+		ch <- res
 		return
 	} else if _, ok := err.(*net.OpError); ok {
 		if t.Status == Unknown || t.Status == OfflineFiltered {
@@ -125,13 +197,22 @@ func (t *Target) scanPort(p *netUtil.Port, ch chan *PortResults, lock *semaphore
 		if strings.HasSuffix(err.Error(), "No connection could be made because the target machine actively refused it.") ||
 			strings.HasSuffix(err.Error(), "connect: connection refused") {
 			res.Closed = append(res.Closed, p)
+			if adaptive {
+				sched.OnSuccess(time.Since(start))
+			}
 		}
 		if strings.HasSuffix(err.Error(), "i/o timeout") {
 			res.Filtered = append(res.Filtered, p)
+			if adaptive {
+				sched.OnTimeout()
+			}
 		}
 		if strings.HasSuffix(err.Error(), "too many open files") {
+			if adaptive {
+				sched.OnTimeout()
+			}
 			time.Sleep(timeOut)
-			go t.scanPort(p, ch, lock)
+			go t.scanPortConnect(p, ch, lock, timeOut, fpOpts)
 			lock.Release(1)
 			return
 		}
@@ -139,3 +220,144 @@ func (t *Target) scanPort(p *netUtil.Port, ch chan *PortResults, lock *semaphore
 	lock.Release(1)
 	ch <- res
 }
+
+// rawSession bundles the raw-socket resources a raw-mode scan needs: one
+// pcap capture (Listener) and one write handle (Sender), opened once per
+// Target and reused across every port probed on it instead of per port.
+// Only the probes themselves (send + wait for reply) are gated behind the
+// concurrency limiter; handle/BPF-filter setup isn't, since it happens once
+// regardless of port count.
+type rawSession struct {
+	listener *rawtcp.Listener
+	sender   *rawtcp.Sender
+	srcPort  uint16
+}
+
+func newRawSession(t *Target, opts ScanOptions) (*rawSession, error) {
+	iface := opts.Iface
+	if iface == nil {
+		var err error
+		iface, err = t.routedInterface()
+		if err != nil {
+			return nil, err
+		}
+	}
+	gwMAC, srcIP, err := t.rawtcpEndpoints(iface)
+	if err != nil {
+		return nil, err
+	}
+
+	srcPort := rawtcp.RandSrcPort()
+	listener, err := rawtcp.NewListener(iface, srcPort)
+	if err != nil {
+		return nil, err
+	}
+	go listener.Run()
+
+	sender, err := rawtcp.NewSender(iface, srcIP, gwMAC)
+	if err != nil {
+		listener.Close()
+		return nil, err
+	}
+	return &rawSession{listener: listener, sender: sender, srcPort: srcPort}, nil
+}
+
+func (s *rawSession) Close() {
+	s.listener.Close()
+	s.sender.Close()
+}
+
+// maybeRawSession opens the rawSession a raw-mode scan of t will probe
+// through, or returns nil for a connect-mode scan (no session needed) or
+// when opening one failed (no routed interface, no known destination MAC,
+// ...); scanPort reports every port Filtered in the latter case.
+func (t *Target) maybeRawSession(opts ScanOptions) *rawSession {
+	switch opts.Mode {
+	case ModeSYN, ModeFIN, ModeNULL, ModeXmas:
+	default:
+		return nil
+	}
+	sess, err := newRawSession(t, opts)
+	if err != nil {
+		return nil
+	}
+	return sess
+}
+
+// scanPortRaw implements the half-open (SYN) and stealth (FIN/NULL/Xmas)
+// modes over sess, a Listener/Sender pair already shared across this
+// Target's whole port list: a bare probe is sent and the handshake is
+// never completed.
+func (t *Target) scanPortRaw(p *netUtil.Port, ch chan *PortResults, lock limiter, opts ScanOptions, sess *rawSession) {
+	res := NewPortResults()
+	sched, adaptive := lock.(*scheduler)
+	timeOut := opts.Timeout
+	if adaptive {
+		timeOut = sched.Timeout()
+	} else if timeOut <= 0 {
+		timeOut = 3000 * time.Millisecond
+	}
+	retries := opts.Retries
+	if retries <= 0 {
+		retries = 2
+	}
+
+	lock.Acquire(context.TODO(), 1)
+	defer lock.Release(1)
+
+	start := time.Now()
+	var reply rawtcp.Reply
+	var got bool
+	for i := 0; i <= retries && !got; i++ {
+		replyCh := sess.listener.Register(t.IPAddr, p.PortNo, sess.srcPort)
+		if sendErr := t.sendRawProbe(sess.sender, opts.Mode, p.PortNo, sess.srcPort); sendErr != nil {
+			break
+		}
+		reply, got = rawtcp.WaitReply(replyCh, timeOut)
+	}
+
+	// FIN/NULL/Xmas are RFC 793 stealth probes: a compliant closed port
+	// answers with RST, but an open one simply never replies at all. That
+	// makes "no reply" the *open* signal in these modes, the opposite of
+	// SYN scanning where silence after retries means filtered.
+	stealth := opts.Mode == ModeFIN || opts.Mode == ModeNULL || opts.Mode == ModeXmas
+
+	switch {
+	case got && reply.Flags.RST:
+		res.Closed = append(res.Closed, p)
+		if adaptive {
+			sched.OnSuccess(time.Since(start))
+		}
+	case got && reply.Flags.SYN && reply.Flags.ACK && !stealth:
+		t.Status = Online
+		res.Open = append(res.Open, p)
+		if adaptive {
+			sched.OnSuccess(time.Since(start))
+		}
+	case !got && stealth:
+		t.Status = Online
+		res.Open = append(res.Open, p)
+		if adaptive {
+			sched.OnSuccess(time.Since(start))
+		}
+	default:
+		res.Filtered = append(res.Filtered, p)
+		if adaptive {
+			sched.OnTimeout()
+		}
+	}
+	ch <- res
+}
+
+func (t *Target) sendRawProbe(sender *rawtcp.Sender, mode ScanMode, dstPort, srcPort uint16) error {
+	switch mode {
+	case ModeFIN:
+		return sender.SendFIN(t.IPAddr, dstPort, srcPort)
+	case ModeNULL:
+		return sender.SendNULL(t.IPAddr, dstPort, srcPort)
+	case ModeXmas:
+		return sender.SendXmas(t.IPAddr, dstPort, srcPort)
+	default:
+		return sender.SendSYN(t.IPAddr, dstPort, srcPort)
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright (c) 2020 Yannic Wehner
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package pScan
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// MarshalJSON renders a Target for machine consumption, spelling out the
+// enum fields (Status/Location) as their String() form instead of the raw
+// int gort uses internally.
+func (t *Target) MarshalJSON() ([]byte, error) {
+	var mac string
+	if t.MACAddr != nil {
+		mac = t.MACAddr.String()
+	}
+	var ip string
+	if t.IPAddr != nil {
+		ip = t.IPAddr.String()
+	}
+	return json.Marshal(struct {
+		HostName      string        `json:"hostName"`
+		Vendor        string        `json:"vendor,omitempty"`
+		IPAddr        string        `json:"ipAddr,omitempty"`
+		MACAddr       string        `json:"macAddr,omitempty"`
+		InitialTarget string        `json:"initialTarget"`
+		Status        string        `json:"status"`
+		Location      string        `json:"location"`
+		Ports         interface{}   `json:"ports,omitempty"`
+		AvgRtt        time.Duration `json:"avgRttNs"`
+	}{
+		HostName:      string(t.HostName),
+		Vendor:        t.Vendor,
+		IPAddr:        ip,
+		MACAddr:       mac,
+		InitialTarget: t.InitialTarget,
+		Status:        t.Status.String(),
+		Location:      t.Location.String(),
+		Ports:         t.Ports,
+		AvgRtt:        t.AvgRtt(),
+	})
+}
+
+// MarshalJSON renders a ScanResult, bucketing the scanned ports into the
+// same Open/Closed/Filtered groups PortResults already keeps internally.
+func (r *ScanResult) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Target    *Target     `json:"target"`
+		StartTime time.Time   `json:"startTime"`
+		EndTime   time.Time   `json:"endTime"`
+		Ports     PortResults `json:"ports"`
+	}{Target: r.Target, StartTime: r.StartTime, EndTime: r.EndTime, Ports: r.Ports})
+}
+
+// MarshalJSON renders a MultiScanResult as {"resolved":[...],"unresolved":[...]}.
+func (m *MultiScanResult) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Resolved   []*ScanResult `json:"resolved"`
+		Unresolved Targets       `json:"unresolved"`
+	}{Resolved: m.Resolved, Unresolved: m.Unresolved})
+}
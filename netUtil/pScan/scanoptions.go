@@ -0,0 +1,97 @@
+// Copyright (c) 2020 Yannic Wehner
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package pScan
+
+import (
+	"net"
+	"time"
+
+	"github.com/ElCap1tan/gort/netUtil/fingerprint"
+)
+
+// ScanMode selects the probe gort sends for every port of a scan.
+type ScanMode int
+
+const (
+	// ModeConnect performs a full TCP three-way handshake via net.DialTimeout.
+	// It needs no special privileges but is slow and trivially logged.
+	ModeConnect ScanMode = iota + 1
+	// ModeSYN sends a bare SYN and never completes the handshake ("half-open"
+	// scanning). Requires raw socket privileges.
+	ModeSYN
+	// ModeFIN, ModeNULL and ModeXmas are RFC 793 stealth variants reusing the
+	// same rawtcp sender/listener pair as ModeSYN: a closed port answers RST,
+	// an open one never replies at all. Requires raw socket privileges.
+	ModeFIN
+	ModeNULL
+	ModeXmas
+)
+
+func (m ScanMode) String() string {
+	switch m {
+	case ModeConnect:
+		return "CONNECT"
+	case ModeSYN:
+		return "SYN"
+	case ModeFIN:
+		return "FIN"
+	case ModeNULL:
+		return "NULL"
+	case ModeXmas:
+		return "XMAS"
+	}
+	return "N/A"
+}
+
+// ScanOptions controls how Target.Scan/Targets.Scan probe each port. In
+// the zero value, Mode falls back to a connect scan and Timeout to 3s;
+// DefaultScanOptions spells those defaults out.
+type ScanOptions struct {
+	Mode ScanMode
+	// Timeout bounds how long a single port probe waits for a reply.
+	Timeout time.Duration
+	// Retries is how many times an unanswered raw-socket probe is resent
+	// before the port is reported Filtered; zero or less means 2. Ignored
+	// by ModeConnect.
+	Retries int
+	// Iface pins the scan to a specific interface. Used by ModeSYN and the
+	// other raw-socket modes; ignored by ModeConnect. When nil, raw
+	// socket modes resolve it from the target's routed interface the same
+	// way Target.QueryMac/Target.IsHost walk net.Interfaces().
+	Iface *net.Interface
+
+	// Policy, when set, replaces the fixed Timeout and the ulimit-sized
+	// semaphore with an AIMD scheduler that adapts concurrency and timeout
+	// to the observed RTT/loss of each target. See ScanPolicy and the
+	// Paranoid/Polite/Normal/Aggressive/Insane presets.
+	Policy *ScanPolicy
+
+	// Fingerprint, when set, runs fingerprint.Probe on every port found
+	// Open (ModeConnect only, since it needs the established connection)
+	// and fills in Port.Service/Product/Version/Banner/TLSInfo.
+	Fingerprint *fingerprint.Options
+}
+
+// DefaultScanOptions reproduces the historical behaviour of Target.Scan: a
+// plain TCP connect scan with a 3s per-port timeout.
+func DefaultScanOptions() ScanOptions {
+	return ScanOptions{Mode: ModeConnect, Timeout: 3000 * time.Millisecond}
+}
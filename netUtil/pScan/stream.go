@@ -0,0 +1,132 @@
+// Copyright (c) 2020 Yannic Wehner
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package pScan
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ElCap1tan/gort/netUtil"
+)
+
+// EventType discriminates the events ScanStream emits.
+type EventType string
+
+const (
+	// EventUnresolved fires once per target whose hostname never resolved.
+	EventUnresolved EventType = "unresolved"
+	// EventPort fires for every port as soon as its probe completes.
+	EventPort EventType = "port"
+	// EventHost fires once all of a target's ports have been probed.
+	EventHost EventType = "host"
+)
+
+// Event is a single step of a streaming scan, marshalled as NDJSON by the
+// caller (one json.Marshal(Event) per line). Port/State are only set on
+// EventPort, Target is set on every event type.
+type Event struct {
+	Type   EventType     `json:"type"`
+	Target *Target       `json:"target"`
+	Port   *netUtil.Port `json:"port,omitempty"`
+	State  string        `json:"state,omitempty"`
+}
+
+// ScanStream is Targets.ScanWithOptions without the barrier at the end: it
+// emits an Event for every port as soon as its probe completes and an
+// EventHost once a target is fully scanned, instead of blocking until every
+// goroutine has finished. events is closed once every target has been
+// processed or ctx is cancelled.
+func (t Targets) ScanStream(ctx context.Context, opts ScanOptions, events chan<- Event) {
+	defer close(events)
+
+	// See ScanWithOptions: the AIMD scheduler is per-target, the plain
+	// ulimit semaphore stays shared across the batch.
+	var shared limiter
+	if opts.Policy == nil {
+		shared = newLimiter(opts)
+	}
+
+	var wg sync.WaitGroup
+	for _, tgt := range t {
+		if tgt.IPAddr == nil {
+			events <- Event{Type: EventUnresolved, Target: tgt}
+			continue
+		}
+		lock := shared
+		if lock == nil {
+			lock = newLimiter(opts)
+		}
+		wg.Add(1)
+		go func(tgt *Target, lock limiter) {
+			defer wg.Done()
+			tgt.scanStream(ctx, lock, opts, events)
+		}(tgt, lock)
+	}
+	wg.Wait()
+}
+
+func (t *Target) scanStream(ctx context.Context, lock limiter, opts ScanOptions, events chan<- Event) {
+	ch := make(chan *PortResults)
+	sess := t.maybeRawSession(opts)
+	for _, p := range t.Ports {
+		go t.scanPort(p, ch, lock, opts, sess)
+	}
+	received := 0
+	for received < len(t.Ports) {
+		select {
+		case <-ctx.Done():
+			// Every scanPort goroutine still running blocks forever on
+			// "ch <- res" if nobody reads the rest: drain them in the
+			// background so they still release their limiter slot and
+			// (for raw modes) stop holding sess's pcap handles open, then
+			// close sess once that's done instead of leaking it here.
+			go drainPortResults(ch, len(t.Ports)-received, sess)
+			return
+		case pr := <-ch:
+			received++
+			for _, p := range pr.Open {
+				events <- Event{Type: EventPort, Target: t, Port: p, State: "open"}
+			}
+			for _, p := range pr.Closed {
+				events <- Event{Type: EventPort, Target: t, Port: p, State: "closed"}
+			}
+			for _, p := range pr.Filtered {
+				events <- Event{Type: EventPort, Target: t, Port: p, State: "filtered"}
+			}
+		}
+	}
+	if sess != nil {
+		sess.Close()
+	}
+	events <- Event{Type: EventHost, Target: t}
+}
+
+// drainPortResults reads off the n in-flight scanPort results a cancelled
+// scanStream abandoned, so their goroutines can return and release sess,
+// then closes sess itself.
+func drainPortResults(ch chan *PortResults, n int, sess *rawSession) {
+	for i := 0; i < n; i++ {
+		<-ch
+	}
+	if sess != nil {
+		sess.Close()
+	}
+}
@@ -21,7 +21,9 @@
 package pScan
 
 import (
+	"bufio"
 	"context"
+	"encoding/binary"
 	"fmt"
 	"github.com/ElCap1tan/gort/internal/colorFmt"
 	"github.com/ElCap1tan/gort/internal/helper"
@@ -34,6 +36,7 @@ import (
 	"github.com/sparrc/go-ping"
 	"golang.org/x/sync/semaphore"
 	"net"
+	"os"
 	"runtime"
 	"strconv"
 	"strings"
@@ -66,16 +69,30 @@ type Target struct {
 	Location      NetworkLocation
 	Ports         netUtil.Ports
 	Rtts          []time.Duration
+	// DiscoveredVia is the Name() of the HostDiscovery probe that found
+	// this target Online, e.g. "icmp-echo" or "tcp-syn-ping". Empty if no
+	// probe succeeded.
+	DiscoveredVia string
 }
 
 func NewTarget(t string, ports netUtil.Ports, privileged bool) *Target {
+	return NewTargetWithOptions(t, ports, privileged, nil)
+}
+
+// NewTargetWithOptions is NewTarget with the alive-probe strategy made
+// explicit (see HostDiscovery) instead of hardcoded to ICMP echo, the same
+// additive pattern Scan/ScanWithOptions already established: it tries each
+// HostDiscovery in order and stops at the first one that reports the host
+// online. A nil discovery falls back to DefaultHostDiscovery, i.e. ICMP
+// echo only, reproducing NewTarget's historical behaviour.
+func NewTargetWithOptions(t string, ports netUtil.Ports, privileged bool, discovery []HostDiscovery) *Target {
+	if discovery == nil {
+		discovery = DefaultHostDiscovery()
+	}
 	h := &Target{InitialTarget: t, Ports: ports, Status: Unknown}
 	h.Resolve()
 	if h.IPAddr != nil {
-		stats, _ := h.Ping(3, privileged)
-		if stats.PacketsRecv > 0 {
-			h.Status = Online
-		}
+		discoverHost(h, discovery, privileged)
 		h.QueryMac()
 		h.LookUpVendor()
 	} else {
@@ -85,16 +102,24 @@ func NewTarget(t string, ports netUtil.Ports, privileged bool) *Target {
 	return h
 }
 
+// AsyncNewTarget is NewTarget run under scanLock for use from
+// ParseHostString's fan-out.
 func AsyncNewTarget(t string, ports netUtil.Ports, ch chan *Target, scanLock *semaphore.Weighted, privileged bool) {
+	AsyncNewTargetWithOptions(t, ports, ch, scanLock, privileged, nil)
+}
+
+// AsyncNewTargetWithOptions is AsyncNewTarget with the alive-probe strategy
+// made explicit; see NewTargetWithOptions.
+func AsyncNewTargetWithOptions(t string, ports netUtil.Ports, ch chan *Target, scanLock *semaphore.Weighted, privileged bool, discovery []HostDiscovery) {
+	if discovery == nil {
+		discovery = DefaultHostDiscovery()
+	}
 	// TODO Add writeMutex
 	scanLock.Acquire(context.TODO(), 4)
 	h := &Target{InitialTarget: t, Ports: ports, Status: Unknown}
 	h.Resolve()
 	if h.IPAddr != nil {
-		stats, _ := h.Ping(3, privileged)
-		if stats.PacketsRecv > 0 {
-			h.Status = Online
-		}
+		discoverHost(h, discovery, privileged)
 		h.QueryMac()
 		h.LookUpVendor()
 	} else {
@@ -106,6 +131,12 @@ func AsyncNewTarget(t string, ports netUtil.Ports, ch chan *Target, scanLock *se
 }
 
 func ParseHostString(hostArgs string, ports netUtil.Ports, privileged bool) Targets {
+	return ParseHostStringWithOptions(hostArgs, ports, privileged, nil)
+}
+
+// ParseHostStringWithOptions is ParseHostString with the alive-probe
+// strategy made explicit; see NewTargetWithOptions.
+func ParseHostStringWithOptions(hostArgs string, ports netUtil.Ports, privileged bool, discovery []HostDiscovery) Targets {
 	var tgtHosts Targets
 	hostCount := 0
 	out := make(chan *Target)
@@ -124,7 +155,7 @@ func ParseHostString(hostArgs string, ports netUtil.Ports, privileged bool) Targ
 	for _, hostArg := range hosts {
 		if ip, ipNet, err := net.ParseCIDR(hostArg); err == nil {
 			for ip := ip.Mask(ipNet.Mask); ipNet.Contains(ip); helper.IncIp(ip) {
-				go AsyncNewTarget(ip.String(), ports, out, lock, privileged)
+				go AsyncNewTargetWithOptions(ip.String(), ports, out, lock, privileged, discovery)
 				hostCount++
 			}
 		} else if helper.ValidateIPOrRange(hostArg) {
@@ -140,15 +171,15 @@ func ParseHostString(hostArgs string, ports netUtil.Ports, privileged bool) Targ
 					}
 				}
 				for _, t := range octetsToTargets(octets) {
-					go AsyncNewTarget(t, ports, out, lock, privileged)
+					go AsyncNewTargetWithOptions(t, ports, out, lock, privileged, discovery)
 					hostCount++
 				}
 			} else {
-				go AsyncNewTarget(hostArg, ports, out, lock, privileged)
+				go AsyncNewTargetWithOptions(hostArg, ports, out, lock, privileged, discovery)
 				hostCount++
 			}
 		} else {
-			go AsyncNewTarget(hostArg, ports, out, lock, privileged)
+			go AsyncNewTargetWithOptions(hostArg, ports, out, lock, privileged, discovery)
 			hostCount++
 		}
 	}
@@ -246,6 +277,130 @@ func (t *Target) QueryMac() {
 	return
 }
 
+// routedInterface walks net.Interfaces the same way QueryMac/IsHost do to
+// find the interface gort would route this target's IP through. For an
+// on-link target that is whichever interface's own subnet contains its IP;
+// for anything off-link (the common case for raw WAN scans) there is no
+// such interface, so it falls back to asking the kernel which interface a
+// connection to the target would use — a UDP "connect" only resolves a
+// route, it never puts a packet on the wire.
+func (t *Target) routedInterface() (*net.Interface, error) {
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+	for i, inf := range interfaces {
+		infAddresses, err := inf.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range infAddresses {
+			if _, ipNet, err := net.ParseCIDR(addr.String()); err == nil && ipNet.Contains(t.IPAddr) {
+				return &interfaces[i], nil
+			}
+		}
+	}
+
+	conn, err := net.Dial("udp", net.JoinHostPort(t.IPAddr.String(), "80"))
+	if err != nil {
+		return nil, fmt.Errorf("rawtcp: no routed interface found for %s", t.IPAddr)
+	}
+	defer conn.Close()
+	localIP := conn.LocalAddr().(*net.UDPAddr).IP
+
+	for i, inf := range interfaces {
+		infAddresses, err := inf.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range infAddresses {
+			if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.Equal(localIP) {
+				return &interfaces[i], nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("rawtcp: no routed interface found for %s", t.IPAddr)
+}
+
+// rawtcpEndpoints resolves the source IP to frame raw probes with and the
+// destination hardware address to send them to. For on-link targets this is
+// the target's own MAC (discovered via QueryMac); for routed targets it
+// falls back to the ARP entry of iface's default gateway.
+func (t *Target) rawtcpEndpoints(iface *net.Interface) (net.HardwareAddr, net.IP, error) {
+	var srcIP net.IP
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.To4() != nil {
+			srcIP = ipNet.IP
+			if ipNet.Contains(t.IPAddr) {
+				break
+			}
+		}
+	}
+	if srcIP == nil {
+		return nil, nil, fmt.Errorf("rawtcp: no IPv4 address on %s", iface.Name)
+	}
+
+	if t.MACAddr == nil {
+		t.QueryMac()
+	}
+	if t.Location == Local && t.MACAddr != nil {
+		return t.MACAddr, srcIP, nil
+	}
+
+	gwIP, err := defaultGateway(iface)
+	if err != nil {
+		return nil, nil, fmt.Errorf("rawtcp: no gateway known for %s: %w", iface.Name, err)
+	}
+	arpCli, err := arp.Dial(iface)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer arpCli.Close()
+	if err := arpCli.SetReadDeadline(time.Now().Add(500 * time.Millisecond)); err != nil {
+		return nil, nil, err
+	}
+	gwMAC, err := arpCli.Resolve(gwIP)
+	if err != nil {
+		return nil, nil, fmt.Errorf("rawtcp: failed to resolve gateway %s: %w", gwIP, err)
+	}
+	return gwMAC, srcIP, nil
+}
+
+// defaultGateway reads iface's default IPv4 route out of /proc/net/route.
+// It only works on Linux; raw WAN scans on other platforms fail here with
+// an honest error instead of silently framing probes to the wrong MAC.
+func defaultGateway(iface *net.Interface) (net.IP, error) {
+	if runtime.GOOS != "linux" {
+		return nil, fmt.Errorf("default gateway lookup unsupported on %s", runtime.GOOS)
+	}
+	f, err := os.Open("/proc/net/route")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Scan() // header line
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 || fields[0] != iface.Name || fields[1] != "00000000" {
+			continue
+		}
+		dst, err := strconv.ParseUint(fields[2], 16, 32)
+		if err != nil {
+			continue
+		}
+		ip := make(net.IP, 4)
+		binary.LittleEndian.PutUint32(ip, uint32(dst))
+		return ip, nil
+	}
+	return nil, fmt.Errorf("no default route found for %s", iface.Name)
+}
+
 func (t *Target) LookUpVendor() {
 	if t.MACAddr != nil {
 		vendorRes, err := macLookup.LookupVendor(t.MACAddr)
@@ -0,0 +1,127 @@
+// Copyright (c) 2020 Yannic Wehner
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package pScan
+
+import "encoding/xml"
+
+// The NmapXXX types below mirror the subset of Nmap's XML output schema
+// (http://nmap.org/book/nmap-dtd.html) gort can actually populate, so
+// existing tooling such as ndiff or XML-based dashboards can ingest a
+// gort scan without a bespoke parser.
+
+type NmapRun struct {
+	XMLName xml.Name   `xml:"nmaprun"`
+	Scanner string     `xml:"scanner,attr"`
+	Hosts   []NmapHost `xml:"host"`
+}
+
+type NmapHost struct {
+	Status    NmapStatus     `xml:"status"`
+	Addresses []NmapAddress  `xml:"address"`
+	Hostnames []NmapHostname `xml:"hostnames>hostname"`
+	Ports     []NmapPort     `xml:"ports>port"`
+}
+
+type NmapStatus struct {
+	State string `xml:"state,attr"`
+}
+
+type NmapAddress struct {
+	Addr     string `xml:"addr,attr"`
+	AddrType string `xml:"addrtype,attr"`
+}
+
+type NmapHostname struct {
+	Name string `xml:"name,attr"`
+}
+
+type NmapPort struct {
+	Protocol string     `xml:"protocol,attr"`
+	PortID   uint16     `xml:"portid,attr"`
+	State    NmapStatus `xml:"state"`
+}
+
+func (t *Target) toNmapHost() NmapHost {
+	state := "down"
+	if t.Status == Online {
+		state = "up"
+	}
+	host := NmapHost{Status: NmapStatus{State: state}}
+	if t.IPAddr != nil {
+		host.Addresses = append(host.Addresses, NmapAddress{Addr: t.IPAddr.String(), AddrType: "ipv4"})
+	}
+	if t.MACAddr != nil {
+		host.Addresses = append(host.Addresses, NmapAddress{Addr: t.MACAddr.String(), AddrType: "mac"})
+	}
+	if t.HostName != "" && t.HostName != "N/A" {
+		host.Hostnames = append(host.Hostnames, NmapHostname{Name: string(t.HostName)})
+	}
+	return host
+}
+
+func portsToNmap(ports []*portXMLEntry) []NmapPort {
+	out := make([]NmapPort, 0, len(ports))
+	for _, p := range ports {
+		out = append(out, NmapPort{Protocol: p.Protocol, PortID: p.PortNo, State: NmapStatus{State: p.State}})
+	}
+	return out
+}
+
+type portXMLEntry struct {
+	PortNo   uint16
+	Protocol string
+	State    string
+}
+
+func (pr PortResults) toXMLEntries() []*portXMLEntry {
+	var entries []*portXMLEntry
+	for _, p := range pr.Open {
+		entries = append(entries, &portXMLEntry{PortNo: p.PortNo, Protocol: p.Protocol, State: "open"})
+	}
+	for _, p := range pr.Closed {
+		entries = append(entries, &portXMLEntry{PortNo: p.PortNo, Protocol: p.Protocol, State: "closed"})
+	}
+	for _, p := range pr.Filtered {
+		entries = append(entries, &portXMLEntry{PortNo: p.PortNo, Protocol: p.Protocol, State: "filtered"})
+	}
+	return entries
+}
+
+// XML renders the scan result as an Nmap-compatible <nmaprun> document
+// containing a single <host>.
+func (r *ScanResult) XML() ([]byte, error) {
+	host := r.Target.toNmapHost()
+	host.Ports = portsToNmap(r.Ports.toXMLEntries())
+	return xml.MarshalIndent(NmapRun{Scanner: "gort", Hosts: []NmapHost{host}}, "", "  ")
+}
+
+// XML renders every resolved target as its own <host> in a single
+// <nmaprun> document; unresolved targets are omitted since Nmap's schema
+// has no equivalent state for "could not be resolved".
+func (m *MultiScanResult) XML() ([]byte, error) {
+	hosts := make([]NmapHost, 0, len(m.Resolved))
+	for _, r := range m.Resolved {
+		host := r.Target.toNmapHost()
+		host.Ports = portsToNmap(r.Ports.toXMLEntries())
+		hosts = append(hosts, host)
+	}
+	return xml.MarshalIndent(NmapRun{Scanner: "gort", Hosts: hosts}, "", "  ")
+}
@@ -1,12 +1,53 @@
 package netUtil
 
-import "fmt"
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/ElCap1tan/gort/netUtil/fingerprint"
+)
 
 type Ports []*Port
 
 type Port struct {
 	PortNo   uint16
 	Protocol string
+
+	// Service, Product, Version and Banner are filled in by
+	// fingerprint.Probe once this port has been found Open; they stay zero
+	// when fingerprinting wasn't requested or nothing matched.
+	Service string
+	Product string
+	Version string
+	Banner  string
+	TLSInfo *fingerprint.TLSInfo
+}
+
+// MarshalJSON renders a Port as {"port":<no>,"protocol":"<proto>",...} so
+// gort's output can be consumed by pipelines without depending on its Go
+// field names. The fingerprinting fields are omitted when empty.
+func (p *Port) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Port     uint16               `json:"port"`
+		Protocol string               `json:"protocol"`
+		Service  string               `json:"service,omitempty"`
+		Product  string               `json:"product,omitempty"`
+		Version  string               `json:"version,omitempty"`
+		Banner   string               `json:"banner,omitempty"`
+		TLSInfo  *fingerprint.TLSInfo `json:"tlsInfo,omitempty"`
+	}{
+		Port: p.PortNo, Protocol: p.Protocol,
+		Service: p.Service, Product: p.Product, Version: p.Version,
+		Banner: p.Banner, TLSInfo: p.TLSInfo,
+	})
+}
+
+// MarshalJSON renders Ports as a plain JSON array; the default behaviour
+// already does this since Ports is a slice, but it's declared explicitly so
+// it keeps working if Ports ever grows non-slice fields.
+func (ps Ports) MarshalJSON() ([]byte, error) {
+	type alias Ports
+	return json.Marshal(alias(ps))
 }
 
 func NewPort(portNo uint16, proto string) *Port {
@@ -50,4 +91,4 @@ func (ps Ports) Preview() string {
 		ret = ret[:len(ret)-2] + "..."
 	}
 	return ret
-}
\ No newline at end of file
+}
@@ -0,0 +1,227 @@
+// Copyright (c) 2020 Yannic Wehner
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package rawtcp provides raw-socket TCP probing (SYN/FIN/NULL/Xmas) for
+// privileged, connection-less port scanning. It requires CAP_NET_RAW /
+// administrator privileges since it crafts and reads packets below the
+// kernel TCP stack.
+package rawtcp
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcap"
+)
+
+// Flags is the set of TCP control bits carried by a Reply.
+type Flags struct {
+	SYN bool
+	ACK bool
+	RST bool
+	FIN bool
+}
+
+// Reply is a single correlated response to an outstanding probe.
+type Reply struct {
+	Flags Flags
+	Err   error
+}
+
+type key struct {
+	dstIP   string
+	dstPort uint16
+	srcPort uint16
+}
+
+// Listener sniffs replies to probes sent via Sender and correlates them back
+// to the caller by destination IP/port and ephemeral source port.
+type Listener struct {
+	handle *pcap.Handle
+	mu     sync.Mutex
+	waiter map[key]chan Reply
+}
+
+// NewListener opens a BPF-filtered pcap capture on iface that only matches
+// TCP segments destined for srcPort, i.e. the replies to our own probes.
+func NewListener(iface *net.Interface, srcPort uint16) (*Listener, error) {
+	handle, err := pcap.OpenLive(iface.Name, 65535, false, pcap.BlockForever)
+	if err != nil {
+		return nil, fmt.Errorf("rawtcp: open %s: %w", iface.Name, err)
+	}
+	if err := handle.SetBPFFilter(fmt.Sprintf("tcp and dst port %d", srcPort)); err != nil {
+		handle.Close()
+		return nil, fmt.Errorf("rawtcp: set filter: %w", err)
+	}
+	return &Listener{handle: handle, waiter: make(map[key]chan Reply)}, nil
+}
+
+// Register reserves a channel that receives the Reply matching dstIP/dstPort/srcPort.
+func (l *Listener) Register(dstIP net.IP, dstPort, srcPort uint16) <-chan Reply {
+	ch := make(chan Reply, 1)
+	l.mu.Lock()
+	l.waiter[key{dstIP.String(), dstPort, srcPort}] = ch
+	l.mu.Unlock()
+	return ch
+}
+
+// Run consumes packets until the handle is closed, dispatching each TCP reply
+// to its registered waiter, if any.
+func (l *Listener) Run() {
+	src := gopacket.NewPacketSource(l.handle, l.handle.LinkType())
+	for pkt := range src.Packets() {
+		ipLayer := pkt.Layer(layers.LayerTypeIPv4)
+		tcpLayer := pkt.Layer(layers.LayerTypeTCP)
+		if ipLayer == nil || tcpLayer == nil {
+			continue
+		}
+		ip := ipLayer.(*layers.IPv4)
+		tcp := tcpLayer.(*layers.TCP)
+
+		l.mu.Lock()
+		k := key{ip.SrcIP.String(), uint16(tcp.SrcPort), uint16(tcp.DstPort)}
+		ch, ok := l.waiter[k]
+		if ok {
+			delete(l.waiter, k)
+		}
+		l.mu.Unlock()
+		if !ok {
+			continue
+		}
+		ch <- Reply{Flags: Flags{SYN: tcp.SYN, ACK: tcp.ACK, RST: tcp.RST, FIN: tcp.FIN}}
+	}
+}
+
+// Close stops the capture and wakes up any probes still waiting for a reply.
+func (l *Listener) Close() {
+	l.handle.Close()
+	l.mu.Lock()
+	for k, ch := range l.waiter {
+		close(ch)
+		delete(l.waiter, k)
+	}
+	l.mu.Unlock()
+}
+
+// Sender crafts and writes bare TCP segments (no handshake completion) onto
+// the wire via a pcap handle of its own, separate from the Listener's.
+type Sender struct {
+	handle *pcap.Handle
+	srcIP  net.IP
+	srcMAC net.HardwareAddr
+	gwMAC  net.HardwareAddr
+}
+
+// NewSender opens its own write handle on iface. gwMAC is the hardware
+// address probes should be framed to, usually the default gateway or the
+// target itself when it's on-link.
+func NewSender(iface *net.Interface, srcIP net.IP, gwMAC net.HardwareAddr) (*Sender, error) {
+	handle, err := pcap.OpenLive(iface.Name, 65535, false, pcap.BlockForever)
+	if err != nil {
+		return nil, fmt.Errorf("rawtcp: open %s: %w", iface.Name, err)
+	}
+	return &Sender{handle: handle, srcIP: srcIP, srcMAC: iface.HardwareAddr, gwMAC: gwMAC}, nil
+}
+
+func (s *Sender) Close() { s.handle.Close() }
+
+// RandSrcPort picks a random source port in 1024-65534 for probes to be
+// sent from and a Listener to filter on.
+func RandSrcPort() uint16 {
+	return uint16(1024 + rand.Intn(64511))
+}
+
+// SendSYN writes a single SYN segment to dstIP:dstPort from srcPort, setting
+// no ACK bit and never completing the handshake.
+func (s *Sender) SendSYN(dstIP net.IP, dstPort, srcPort uint16) error {
+	return s.send(dstIP, dstPort, srcPort, layers.TCP{SYN: true})
+}
+
+// SendACK writes a bare ACK segment, used for TCP ACK host/firewall probing
+// rather than port scanning: a stateless firewall will pass it through and
+// the host will answer with a RST regardless of whether the port is open.
+func (s *Sender) SendACK(dstIP net.IP, dstPort, srcPort uint16) error {
+	return s.send(dstIP, dstPort, srcPort, layers.TCP{ACK: true})
+}
+
+// SendFIN writes a bare FIN segment (FIN scan).
+func (s *Sender) SendFIN(dstIP net.IP, dstPort, srcPort uint16) error {
+	return s.send(dstIP, dstPort, srcPort, layers.TCP{FIN: true})
+}
+
+// SendNULL writes a segment with no control flags set (NULL scan).
+func (s *Sender) SendNULL(dstIP net.IP, dstPort, srcPort uint16) error {
+	return s.send(dstIP, dstPort, srcPort, layers.TCP{})
+}
+
+// SendXmas writes a segment with FIN, PSH and URG set (Xmas scan).
+func (s *Sender) SendXmas(dstIP net.IP, dstPort, srcPort uint16) error {
+	return s.send(dstIP, dstPort, srcPort, layers.TCP{FIN: true, PSH: true, URG: true})
+}
+
+func (s *Sender) send(dstIP net.IP, dstPort, srcPort uint16, tcp layers.TCP) error {
+	eth := layers.Ethernet{SrcMAC: s.srcMAC, DstMAC: s.gwMAC, EthernetType: layers.EthernetTypeIPv4}
+	ip := layers.IPv4{
+		Version:  4,
+		TTL:      64,
+		Id:       uint16(binary.BigEndian.Uint16(randBytes(2))),
+		Protocol: layers.IPProtocolTCP,
+		SrcIP:    s.srcIP,
+		DstIP:    dstIP,
+	}
+	tcp.SrcPort = layers.TCPPort(srcPort)
+	tcp.DstPort = layers.TCPPort(dstPort)
+	tcp.Seq = rand.Uint32()
+	tcp.Window = 1024
+	if err := tcp.SetNetworkLayerForChecksum(&ip); err != nil {
+		return err
+	}
+
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+	if err := gopacket.SerializeLayers(buf, opts, &eth, &ip, &tcp); err != nil {
+		return fmt.Errorf("rawtcp: serialize: %w", err)
+	}
+	return s.handle.WritePacketData(buf.Bytes())
+}
+
+func randBytes(n int) []byte {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return b
+}
+
+// WaitReply blocks on ch until a Reply arrives or timeout elapses. ok is
+// false on timeout or when ch was closed (no response at all); what that
+// silence means for the port is left to the caller.
+func WaitReply(ch <-chan Reply, timeout time.Duration) (Reply, bool) {
+	select {
+	case r, ok := <-ch:
+		return r, ok
+	case <-time.After(timeout):
+		return Reply{}, false
+	}
+}